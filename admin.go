@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// adminJobsHandler serves the admin-only job management routes: GET
+// /admin/jobs lists every tracked job's status snapshot, and DELETE
+// /admin/jobs/{id} drops one from the store. Deleting a job only forgets
+// it; a worker already running it runs to completion.
+func adminJobsHandler(store *jobStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/admin/jobs/"), "/")
+
+		switch {
+		case r.Method == http.MethodGet && id == "":
+			jobs := store.list()
+			resp := make([]jobStatusResponse, 0, len(jobs))
+			for _, j := range jobs {
+				resp = append(resp, j.statusResponse())
+			}
+			json.NewEncoder(w).Encode(resp)
+
+		case r.Method == http.MethodDelete && id != "":
+			if !store.delete(id) {
+				writeError(w, "unknown job id", http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]bool{"success": true})
+
+		default:
+			writeError(w, "unsupported method", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// adminFilesHandler serves DELETE /admin/files/{name}, removing a stored
+// output ahead of its normal expiration.
+func adminFilesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/admin/files/"), "/")
+	if r.Method != http.MethodDelete || name == "" {
+		writeError(w, "unsupported method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := store.Delete(name); err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
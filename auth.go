@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type keyRole string
+
+const (
+	roleStandard keyRole = "standard"
+	roleAdmin    keyRole = "admin"
+)
+
+const (
+	defaultRateLimitPerKeyPerMin = 30
+	defaultRateLimitPerIPPerMin  = 60
+)
+
+type authContextKey struct{}
+
+// apiKeyStore maps API keys to the role they authenticate as. A nil store
+// means auth is disabled, so local development doesn't need a key file.
+type apiKeyStore struct {
+	keys map[string]keyRole
+}
+
+type apiKeyFileEntry struct {
+	Key  string  `json:"key"`
+	Role keyRole `json:"role"`
+}
+
+// loadAPIKeyStoreFromEnv loads keys from API_KEYS_FILE (a JSON array of
+// {"key","role"} entries) if set, falling back to the inline API_KEYS env
+// var ("key:role,key2:role2", role defaulting to standard). Neither set
+// means auth is disabled.
+func loadAPIKeyStoreFromEnv() (*apiKeyStore, error) {
+	if path := os.Getenv("API_KEYS_FILE"); path != "" {
+		return loadAPIKeyStoreFromFile(path)
+	}
+	if raw := os.Getenv("API_KEYS"); raw != "" {
+		return parseAPIKeys(raw)
+	}
+	return nil, nil
+}
+
+func loadAPIKeyStoreFromFile(path string) (*apiKeyStore, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading API_KEYS_FILE: %w", err)
+	}
+
+	var entries []apiKeyFileEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("parsing API_KEYS_FILE: %w", err)
+	}
+
+	keys := make(map[string]keyRole, len(entries))
+	for _, e := range entries {
+		role := e.Role
+		if role == "" {
+			role = roleStandard
+		}
+		keys[e.Key] = role
+	}
+	return &apiKeyStore{keys: keys}, nil
+}
+
+func parseAPIKeys(raw string) (*apiKeyStore, error) {
+	keys := make(map[string]keyRole)
+	for _, pair := range strings.Split(raw, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		key, role := pair, roleStandard
+		if idx := strings.IndexByte(pair, ':'); idx >= 0 {
+			key = pair[:idx]
+			if pair[idx+1:] == string(roleAdmin) {
+				role = roleAdmin
+			}
+		}
+		keys[key] = role
+	}
+	return &apiKeyStore{keys: keys}, nil
+}
+
+func (s *apiKeyStore) role(key string) (keyRole, bool) {
+	if s == nil || key == "" {
+		return "", false
+	}
+	role, ok := s.keys[key]
+	return role, ok
+}
+
+// extractAPIKey reads the caller's key from an Authorization: Bearer header
+// or, failing that, X-API-Key. The SSE job-progress stream (/jobs/{id}/events)
+// also accepts the key as an api_key query param, since a browser's
+// EventSource can't set custom request headers at all.
+func extractAPIKey(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	if strings.HasSuffix(r.URL.Path, "/events") {
+		return r.URL.Query().Get("api_key")
+	}
+	return ""
+}
+
+// tokenBucket is a classic token-bucket limiter: capacity tokens, refilled
+// continuously at refillRate per second, one token spent per allowed call.
+type tokenBucket struct {
+	mu         sync.Mutex
+	tokens     float64
+	capacity   float64
+	refillRate float64
+	last       time.Time
+}
+
+func newTokenBucket(capacity, refillRate float64) *tokenBucket {
+	return &tokenBucket{tokens: capacity, capacity: capacity, refillRate: refillRate, last: time.Now()}
+}
+
+func (b *tokenBucket) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens = math.Min(b.capacity, b.tokens+now.Sub(b.last).Seconds()*b.refillRate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// rateLimiter hands out a tokenBucket per distinct identifier (an API key
+// or an IP), same as jobStore's map of jobs: identifiers are never evicted,
+// but the set of callers hitting this API is bounded in practice.
+type rateLimiter struct {
+	mu         sync.Mutex
+	buckets    map[string]*tokenBucket
+	capacity   float64
+	refillRate float64
+}
+
+func newRateLimiter(perMinute float64) *rateLimiter {
+	return &rateLimiter{
+		buckets:    make(map[string]*tokenBucket),
+		capacity:   perMinute,
+		refillRate: perMinute / 60,
+	}
+}
+
+func (l *rateLimiter) allow(id string) bool {
+	l.mu.Lock()
+	b, ok := l.buckets[id]
+	if !ok {
+		b = newTokenBucket(l.capacity, l.refillRate)
+		l.buckets[id] = b
+	}
+	l.mu.Unlock()
+
+	return b.allow()
+}
+
+// apiRateLimiter enforces both a per-key and a per-source-IP budget, so one
+// leaked key can't starve everyone else sharing the service and one noisy
+// IP can't exhaust a key it doesn't own.
+type apiRateLimiter struct {
+	perKey *rateLimiter
+	perIP  *rateLimiter
+}
+
+func newAPIRateLimiter(perKeyPerMin, perIPPerMin float64) *apiRateLimiter {
+	return &apiRateLimiter{
+		perKey: newRateLimiter(perKeyPerMin),
+		perIP:  newRateLimiter(perIPPerMin),
+	}
+}
+
+func (l *apiRateLimiter) allow(key string, r *http.Request) bool {
+	// Check the IP bucket first: a caller already over its own per-IP
+	// budget shouldn't also spend a token from the shared per-key bucket,
+	// or it could starve every other legitimate holder of that key.
+	if !l.perIP.allow(clientIP(r)) {
+		return false
+	}
+	return l.perKey.allow(key)
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// requireAuth enforces API-key auth and the rate limiter ahead of h. If
+// apiKeys is nil (no API_KEYS/API_KEYS_FILE configured), it's a no-op so
+// local development doesn't need a key — and every caller is treated as
+// roleAdmin, so requireAdmin-gated routes stay reachable too, consistent
+// with the rest of the API being wide open in that mode.
+func requireAuth(apiKeys *apiKeyStore, limiter *apiRateLimiter, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if apiKeys == nil {
+			h(w, r.WithContext(context.WithValue(r.Context(), authContextKey{}, roleAdmin)))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		key := extractAPIKey(r)
+		role, ok := apiKeys.role(key)
+		if !ok {
+			writeError(w, "invalid or missing API key", http.StatusUnauthorized)
+			return
+		}
+
+		if !limiter.allow(key, r) {
+			writeError(w, "rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+
+		h(w, r.WithContext(context.WithValue(r.Context(), authContextKey{}, role)))
+	}
+}
+
+// requireAdmin rejects requests whose key (set by requireAuth) isn't
+// roleAdmin. It must sit behind requireAuth in the handler chain.
+func requireAdmin(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		role, _ := r.Context().Value(authContextKey{}).(keyRole)
+		if role != roleAdmin {
+			w.Header().Set("Content-Type", "application/json")
+			writeError(w, "admin API key required", http.StatusForbidden)
+			return
+		}
+		h(w, r)
+	}
+}
+
+func floatEnv(name string, def float64) float64 {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketAllowsBurstUpToCapacityThenBlocks(t *testing.T) {
+	b := newTokenBucket(3, 1) // 3 tokens, refill 1/sec
+
+	for i := 0; i < 3; i++ {
+		if !b.allow() {
+			t.Fatalf("expected token %d to be allowed", i)
+		}
+	}
+	if b.allow() {
+		t.Fatal("expected bucket to be empty after the burst")
+	}
+}
+
+func TestTokenBucketRefillsOverTime(t *testing.T) {
+	b := newTokenBucket(1, 1000) // refill fast so the test doesn't sleep long
+
+	if !b.allow() {
+		t.Fatal("expected the first token to be allowed")
+	}
+	if b.allow() {
+		t.Fatal("expected the bucket to be empty right after spending its only token")
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	if !b.allow() {
+		t.Fatal("expected the bucket to have refilled after waiting")
+	}
+}
+
+func TestRateLimiterTracksEachKeyIndependently(t *testing.T) {
+	l := newRateLimiter(1)
+
+	if !l.allow("a") {
+		t.Fatal("expected the first request for key a to be allowed")
+	}
+	if l.allow("a") {
+		t.Fatal("expected the second request for key a to be blocked")
+	}
+	if !l.allow("b") {
+		t.Fatal("expected key b to have its own independent budget")
+	}
+}
+
+func TestAPIRateLimiterChecksIPBeforeSpendingKeyBudget(t *testing.T) {
+	limiter := newAPIRateLimiter(2, 1)
+
+	reqA := httptest.NewRequest(http.MethodPost, "/convert", nil)
+	reqA.RemoteAddr = "10.0.0.1:1111"
+
+	reqB := httptest.NewRequest(http.MethodPost, "/convert", nil)
+	reqB.RemoteAddr = "10.0.0.2:2222"
+
+	// Exhaust 10.0.0.1's own IP budget. It should never touch the shared
+	// key's budget, so 10.0.0.2 using the same key is unaffected.
+	if !limiter.allow("shared-key", reqA) {
+		t.Fatal("expected the first request from 10.0.0.1 to be allowed")
+	}
+	if limiter.allow("shared-key", reqA) {
+		t.Fatal("expected the second request from 10.0.0.1 to be blocked on its own IP budget")
+	}
+	if limiter.allow("shared-key", reqA) {
+		t.Fatal("expected the third request from 10.0.0.1 to still be blocked on IP, not spend the key budget")
+	}
+
+	if !limiter.allow("shared-key", reqB) {
+		t.Fatal("expected 10.0.0.2 to still have its key budget intact")
+	}
+}
+
+func TestRequireAuthWithNoKeysConfiguredAllowsAdminRoutesThrough(t *testing.T) {
+	h := requireAuth(nil, newAPIRateLimiter(1000, 1000), requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "/admin/jobs/", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected admin route to be reachable with auth disabled, got status %d", rec.Code)
+	}
+}
+
+func TestParseAPIKeysDefaultsToStandardRole(t *testing.T) {
+	store, err := parseAPIKeys("plainkey, adminkey:admin")
+	if err != nil {
+		t.Fatalf("parseAPIKeys: %v", err)
+	}
+
+	if role, ok := store.role("plainkey"); !ok || role != roleStandard {
+		t.Fatalf("expected plainkey to be roleStandard, got %q (ok=%v)", role, ok)
+	}
+	if role, ok := store.role("adminkey"); !ok || role != roleAdmin {
+		t.Fatalf("expected adminkey to be roleAdmin, got %q (ok=%v)", role, ok)
+	}
+	if _, ok := store.role("unknown"); ok {
+		t.Fatal("expected an unknown key to be rejected")
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// expiryIndex tracks when each named object should be deleted, persisted
+// to a JSON file on local disk so a restart doesn't forget it. Every
+// Storage backend uses one of these, including the remote ones: presigned
+// URLs and bucket lifecycle rules don't reclaim the object itself, so
+// something still has to sweep and delete it.
+type expiryIndex struct {
+	path string
+
+	mu      sync.Mutex
+	entries map[string]time.Time
+}
+
+func newExpiryIndex(path string) *expiryIndex {
+	idx := &expiryIndex{path: path, entries: make(map[string]time.Time)}
+	idx.load()
+	return idx
+}
+
+func (idx *expiryIndex) set(name string, expiresAt time.Time) {
+	idx.mu.Lock()
+	idx.entries[name] = expiresAt
+	idx.mu.Unlock()
+	idx.save()
+}
+
+func (idx *expiryIndex) remove(name string) {
+	idx.mu.Lock()
+	delete(idx.entries, name)
+	idx.mu.Unlock()
+	idx.save()
+}
+
+func (idx *expiryIndex) expired(now time.Time) []string {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	var out []string
+	for name, expiresAt := range idx.entries {
+		if now.After(expiresAt) {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+func (idx *expiryIndex) load() {
+	data, err := os.ReadFile(idx.path)
+	if err != nil {
+		return
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	json.Unmarshal(data, &idx.entries)
+}
+
+func (idx *expiryIndex) save() {
+	idx.mu.Lock()
+	data, err := json.Marshal(idx.entries)
+	idx.mu.Unlock()
+	if err != nil {
+		return
+	}
+
+	if err := os.WriteFile(idx.path, data, 0644); err != nil {
+		log.Printf("storage: failed to persist expiration index %s: %v", idx.path, err)
+	}
+}
+
+// sweep calls fn (typically a Storage's Delete) for everything expired as
+// of now. fn is expected to remove the name from idx itself. Every Delete in
+// this package does so before deleting the object, so a name whose delete
+// fails is logged and dropped from the index, not retried on a later sweep.
+func (idx *expiryIndex) sweep(fn func(name string) error) {
+	for _, name := range idx.expired(time.Now()) {
+		if err := fn(name); err != nil {
+			log.Printf("storage: failed to sweep %s: %v", name, err)
+		}
+	}
+}
+
+// sweepLoop runs sweep on a fixed interval until the process exits.
+func (idx *expiryIndex) sweepLoop(interval time.Duration, fn func(name string) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		idx.sweep(fn)
+	}
+}
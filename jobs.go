@@ -0,0 +1,331 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+type jobStatus string
+
+const (
+	jobQueued  jobStatus = "queued"
+	jobRunning jobStatus = "running"
+	jobDone    jobStatus = "done"
+	jobFailed  jobStatus = "failed"
+)
+
+const jobWorkerCount = 4
+
+// job tracks the lifecycle of a single async conversion, from the moment
+// it's queued until its output is ready (or it fails). Progress lines are
+// appended as wkhtmltopdf reports them so /jobs/{id}/events can replay them.
+type job struct {
+	mu         sync.Mutex
+	id         string
+	status     jobStatus
+	createdAt  time.Time
+	startedAt  time.Time
+	finishedAt time.Time
+	link       string
+	err        string
+	progress   []string
+	listeners  map[chan string]struct{}
+}
+
+func newJob(id string) *job {
+	return &job{
+		id:        id,
+		status:    jobQueued,
+		createdAt: time.Now(),
+		listeners: make(map[chan string]struct{}),
+	}
+}
+
+func (j *job) appendProgress(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return
+	}
+
+	j.mu.Lock()
+	j.progress = append(j.progress, line)
+	for ch := range j.listeners {
+		select {
+		case ch <- line:
+		default:
+		}
+	}
+	j.mu.Unlock()
+}
+
+func (j *job) setStatus(s jobStatus) {
+	j.mu.Lock()
+	j.status = s
+	switch s {
+	case jobRunning:
+		j.startedAt = time.Now()
+	case jobDone, jobFailed:
+		j.finishedAt = time.Now()
+	}
+	j.mu.Unlock()
+}
+
+func (j *job) fail(err error) {
+	j.mu.Lock()
+	j.status = jobFailed
+	j.err = err.Error()
+	j.finishedAt = time.Now()
+	j.mu.Unlock()
+}
+
+func (j *job) complete(link string) {
+	j.mu.Lock()
+	j.status = jobDone
+	j.link = link
+	j.finishedAt = time.Now()
+	j.mu.Unlock()
+}
+
+func (j *job) subscribe() chan string {
+	ch := make(chan string, 16)
+	j.mu.Lock()
+	j.listeners[ch] = struct{}{}
+	j.mu.Unlock()
+	return ch
+}
+
+func (j *job) unsubscribe(ch chan string) {
+	j.mu.Lock()
+	delete(j.listeners, ch)
+	j.mu.Unlock()
+	close(ch)
+}
+
+func (j *job) elapsedMillis() int64 {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.startedAt.IsZero() {
+		return 0
+	}
+	if j.finishedAt.IsZero() {
+		return time.Since(j.startedAt).Milliseconds()
+	}
+	return j.finishedAt.Sub(j.startedAt).Milliseconds()
+}
+
+type jobStatusResponse struct {
+	ID         string `json:"id"`
+	Status     string `json:"status"`
+	ElapsedMS  int64  `json:"elapsed_ms"`
+	OutputLink string `json:"output_link,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+func (j *job) statusResponse() jobStatusResponse {
+	elapsed := j.elapsedMillis()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return jobStatusResponse{
+		ID:         j.id,
+		Status:     string(j.status),
+		ElapsedMS:  elapsed,
+		OutputLink: j.link,
+		Error:      j.err,
+	}
+}
+
+// jobStore is an in-memory registry of in-flight and completed jobs. Jobs
+// are never evicted on their own, and nothing is persisted: a restart
+// simply forgets about them, unlike the Storage backend's expiryIndex,
+// which is saved to disk and picked back up.
+type jobStore struct {
+	mu   sync.Mutex
+	jobs map[string]*job
+}
+
+func newJobStore() *jobStore {
+	return &jobStore{jobs: make(map[string]*job)}
+}
+
+func (s *jobStore) add(j *job) {
+	s.mu.Lock()
+	s.jobs[j.id] = j
+	s.mu.Unlock()
+}
+
+func (s *jobStore) get(id string) (*job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, ok := s.jobs[id]
+	return j, ok
+}
+
+func (s *jobStore) list() []*job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]*job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		out = append(out, j)
+	}
+	return out
+}
+
+func (s *jobStore) delete(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jobs[id]; !ok {
+		return false
+	}
+	delete(s.jobs, id)
+	return true
+}
+
+// conversionTask is the unit of work handed to the worker pool.
+type conversionTask struct {
+	job    *job
+	html   string
+	opts   RenderOptions
+	prefix string
+	server string
+}
+
+// startJobWorkers launches the fixed-size pool that drains tasks from
+// queue. Keeping the pool small bounds how many wkhtmltopdf processes run
+// concurrently, since each one is memory-hungry.
+func startJobWorkers(queue <-chan conversionTask) {
+	for i := 0; i < jobWorkerCount; i++ {
+		go func() {
+			for task := range queue {
+				runConversionTask(task)
+			}
+		}()
+	}
+}
+
+func runConversionTask(task conversionTask) {
+	task.job.setStatus(jobRunning)
+
+	progress := &jobProgressWriter{job: task.job}
+	name, err := convertAndStore(task.html, task.opts, task.prefix, lifeTime, progress)
+	if err != nil {
+		task.job.fail(err)
+		return
+	}
+
+	link, err := store.URL(name, lifeTime)
+	if err != nil {
+		task.job.fail(err)
+		return
+	}
+
+	expiry := time.Now().Add(lifeTime)
+	task.job.complete(resolveLink(link, task.server))
+	task.job.appendProgress(fmt.Sprintf("done, expires at %s", expiry.UTC().Format(time.RFC3339)))
+}
+
+// jobProgressWriter adapts wkhtmltopdf's stderr stream into job progress
+// lines so SSE subscribers see them as they're produced.
+type jobProgressWriter struct {
+	job *job
+}
+
+func (w *jobProgressWriter) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(string(p), "\n") {
+		w.job.appendProgress(line)
+	}
+	return len(p), nil
+}
+
+// jobsHandler serves GET /jobs/{id} with the current status snapshot.
+func jobsHandler(store *jobStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		id := strings.TrimPrefix(r.URL.Path, "/jobs/")
+		id = strings.TrimSuffix(id, "/events")
+		if id == "" {
+			writeError(w, "job id is required", http.StatusBadRequest)
+			return
+		}
+
+		j, ok := store.get(id)
+		if !ok {
+			writeError(w, "unknown job id", http.StatusNotFound)
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/events") {
+			streamJobEvents(w, r, j)
+			return
+		}
+
+		json.NewEncoder(w).Encode(j.statusResponse())
+	}
+}
+
+// streamJobEvents serves GET /jobs/{id}/events as Server-Sent Events,
+// replaying buffered progress lines before switching to live updates.
+func streamJobEvents(w http.ResponseWriter, r *http.Request, j *job) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	ch := j.subscribe()
+	defer j.unsubscribe(ch)
+
+	j.mu.Lock()
+	backlog := append([]string(nil), j.progress...)
+	status := j.status
+	j.mu.Unlock()
+
+	for _, line := range backlog {
+		fmt.Fprintf(w, "data: %s\n\n", line)
+	}
+	flusher.Flush()
+
+	if status == jobDone || status == jobFailed {
+		fmt.Fprintf(w, "event: %s\ndata: done\n\n", status)
+		flusher.Flush()
+		return
+	}
+
+	for {
+		select {
+		case line, ok := <-ch:
+			if !ok {
+				return
+			}
+			fmt.Fprintf(w, "data: %s\n\n", line)
+			flusher.Flush()
+		case <-time.After(15 * time.Second):
+			fmt.Fprintf(w, ": keepalive\n\n")
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+
+		resp := j.statusResponse()
+		if resp.Status == string(jobDone) || resp.Status == string(jobFailed) {
+			fmt.Fprintf(w, "event: %s\ndata: done\n\n", resp.Status)
+			flusher.Flush()
+			return
+		}
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -10,19 +11,30 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
-
-	wkhtml "github.com/SebastiaanKlippert/go-wkhtmltopdf"
 )
 
 const (
 	tempDir  = "./temp"
 	lifeTime = 5 * time.Minute
+
+	jobQueueSize = 64
+
+	maxHTMLBodyBytes = 20 << 20 // 20MB
 )
 
 type Request struct {
 	HTML   string `json:"html"`
 	Prefix string `json:"prefix"`
+	Async  bool   `json:"async"`
+	RenderOptions
+}
+
+type AsyncResponse struct {
+	Success   bool   `json:"success"`
+	JobID     string `json:"job_id"`
+	StatusURL string `json:"status_url"`
 }
 
 type SuccessResponse struct {
@@ -37,11 +49,40 @@ type ErrorResponse struct {
 	Error   string `json:"error"`
 }
 
+var (
+	jobs     = newJobStore()
+	jobQueue = make(chan conversionTask, jobQueueSize)
+	store    Storage
+)
+
 func main() {
 	os.MkdirAll(tempDir, 0755)
 
-	http.HandleFunc("/convert", cors(convertHandler))
-	http.Handle("/files/", http.StripPrefix("/files/", http.FileServer(http.Dir(tempDir))))
+	var err error
+	store, err = newStorageFromEnv()
+	if err != nil {
+		log.Fatalf("storage: %v", err)
+	}
+
+	apiKeys, err := loadAPIKeyStoreFromEnv()
+	if err != nil {
+		log.Fatalf("auth: %v", err)
+	}
+	if apiKeys == nil {
+		log.Println("auth: no API_KEYS/API_KEYS_FILE configured, running with auth disabled")
+	}
+	limiter := newAPIRateLimiter(
+		floatEnv("RATE_LIMIT_PER_KEY_PER_MIN", defaultRateLimitPerKeyPerMin),
+		floatEnv("RATE_LIMIT_PER_IP_PER_MIN", defaultRateLimitPerIPPerMin),
+	)
+
+	startJobWorkers(jobQueue)
+
+	http.HandleFunc("/convert", cors(requireAuth(apiKeys, limiter, convertHandler)))
+	http.HandleFunc("/jobs/", cors(requireAuth(apiKeys, limiter, jobsHandler(jobs))))
+	http.HandleFunc("/admin/jobs/", cors(requireAuth(apiKeys, limiter, requireAdmin(adminJobsHandler(jobs)))))
+	http.HandleFunc("/admin/files/", cors(requireAuth(apiKeys, limiter, requireAdmin(adminFilesHandler))))
+	http.Handle("/files/", http.StripPrefix("/files/", conditionalGet(http.FileServer(http.Dir(tempDir)))))
 
 	log.Println("PDF API running on :8080")
 	log.Fatal(http.ListenAndServe(":8080", nil))
@@ -49,22 +90,41 @@ func main() {
 
 func convertHandler(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
-	w.Header().Set("Content-Type", "application/json")
 
 	if r.Method != http.MethodPost {
+		w.Header().Set("Content-Type", "application/json")
 		writeError(w, "POST only", http.StatusMethodNotAllowed)
 		return
 	}
 
-	body, _ := io.ReadAll(r.Body)
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		multipartConvertHandler(w, r)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxHTMLBodyBytes)
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		writeError(w, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
 
 	var req Request
 	if err := json.Unmarshal(body, &req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
 		writeError(w, "invalid json body", http.StatusBadRequest)
 		return
 	}
 
+	// Re-encoding the decoded request normalizes whitespace and key order,
+	// so equivalent bodies hash to the same ETag.
+	normalized, _ := json.Marshal(req)
+	etag := fmt.Sprintf(`"%x"`, sha256.Sum256(normalized))
+
 	if req.HTML == "" {
+		w.Header().Set("Content-Type", "application/json")
 		writeError(w, "html is required", http.StatusBadRequest)
 		return
 	}
@@ -74,53 +134,135 @@ func convertHandler(w http.ResponseWriter, r *http.Request) {
 		prefix = "file"
 	}
 
-	filename := buildFileName(prefix)
-	fullPath := filepath.Join(tempDir, filename)
+	if r.URL.Query().Get("stream") == "1" {
+		streamConvertHandler(w, req, etag)
+		return
+	}
 
-	if err := htmlToPDF(req.HTML, fullPath); err != nil {
-		writeError(w, err.Error(), http.StatusInternalServerError)
+	w.Header().Set("Content-Type", "application/json")
+
+	isAsync := req.Async || r.URL.Query().Get("async") == "1"
+	if isAsync {
+		enqueueConversion(w, r, req, prefix)
 		return
 	}
 
-	expiry := time.Now().Add(lifeTime)
-	go deleteLater(fullPath)
+	name, err := convertAndStore(req.HTML, req.RenderOptions, prefix, lifeTime, nil)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-	server := getServerURL(r)
+	link, err := store.URL(name, lifeTime)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
 	resp := SuccessResponse{
 		Success:     true,
-		Link:        fmt.Sprintf("%s/files/%s", server, filename),
-		ExpiresAt:   expiry.UTC(),
+		Link:        resolveLink(link, getServerURL(r)),
+		ExpiresAt:   time.Now().Add(lifeTime).UTC(),
 		TimeElapsed: time.Since(start).Milliseconds(),
 	}
 
 	json.NewEncoder(w).Encode(resp)
 }
 
-func htmlToPDF(html string, output string) error {
-	pdfg, err := wkhtml.NewPDFGenerator()
+// streamConvertHandler pipes wkhtmltopdf's stdout straight into the
+// response as application/pdf instead of writing a temp file, so one-shot
+// callers skip the disk round-trip. The ETag is the hash of the normalized
+// request body rather than the rendered PDF, so it's known up front and a
+// CDN can key its cache on it for repeated identical inputs.
+//
+// The generator (including resolving the wkhtmltopdf binary) is built
+// before the status line is written, so a config or setup error still
+// comes back as a normal JSON 500 instead of a 200 with a truncated body.
+func streamConvertHandler(w http.ResponseWriter, req Request, etag string) {
+	pdfg, err := newGenerator(req.HTML, req.RenderOptions, nil)
 	if err != nil {
-		return err
+		w.Header().Set("Content-Type", "application/json")
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
-	page := wkhtml.NewPageReader(io.NopCloser(&stringReader{html}))
-	page.EnableLocalFileAccess.Set(true)
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("ETag", etag)
+	w.WriteHeader(http.StatusOK)
 
-	pdfg.AddPage(page)
+	pdfg.SetOutput(w)
+	if err := pdfg.Create(); err != nil {
+		log.Printf("stream convert failed: %v", err)
+	}
+}
 
-	// 0.25 inch ≈ 6mm
-	pdfg.MarginTop.Set(6)
-	pdfg.MarginBottom.Set(6)
-	pdfg.MarginLeft.Set(6)
-	pdfg.MarginRight.Set(6)
+// convertAndStore renders html (or opts.Pages, if set) to a scratch PDF
+// and hands it off to the configured Storage backend under a fresh name,
+// returning that name for the caller to build a link from.
+func convertAndStore(html string, opts RenderOptions, prefix string, ttl time.Duration, stderr io.Writer) (string, error) {
+	scratch, err := os.CreateTemp("", prefix+"-*.pdf")
+	if err != nil {
+		return "", err
+	}
+	scratchPath := scratch.Name()
+	scratch.Close()
+	defer os.Remove(scratchPath)
 
-	pdfg.PageSize.Set(wkhtml.PageSizeA4)
+	if err := renderPDF(html, opts, scratchPath, stderr); err != nil {
+		return "", err
+	}
 
-	if err := pdfg.Create(); err != nil {
-		return err
+	f, err := os.Open(scratchPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	name := buildFileName(prefix)
+	if err := store.Put(name, f, ttl); err != nil {
+		return "", err
+	}
+
+	return name, nil
+}
+
+// resolveLink turns a Storage URL into something callers can follow
+// directly: remote backends already return an absolute presigned URL, local
+// storage returns a path relative to this server.
+func resolveLink(link, server string) string {
+	if strings.HasPrefix(link, "http://") || strings.HasPrefix(link, "https://") {
+		return link
+	}
+	return server + link
+}
+
+// enqueueConversion records a queued job and hands it to the worker pool,
+// immediately replying with the job id and where to poll or stream from.
+func enqueueConversion(w http.ResponseWriter, r *http.Request, req Request, prefix string) {
+	id := buildJobID()
+	j := newJob(id)
+	jobs.add(j)
+
+	server := getServerURL(r)
+
+	select {
+	case jobQueue <- conversionTask{job: j, html: req.HTML, opts: req.RenderOptions, prefix: prefix, server: server}:
+	default:
+		j.fail(fmt.Errorf("job queue is full, try again shortly"))
 	}
 
-	return pdfg.WriteFile(output)
+	resp := AsyncResponse{
+		Success:   true,
+		JobID:     id,
+		StatusURL: fmt.Sprintf("%s/jobs/%s", server, id),
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func buildJobID() string {
+	hashSource := fmt.Sprintf("job_%d", time.Now().UnixNano())
+	h := sha1.Sum([]byte(hashSource))
+	return hex.EncodeToString(h[:])[:16]
 }
 
 func writeError(w http.ResponseWriter, msg string, status int) {
@@ -162,15 +304,31 @@ func getServerURL(r *http.Request) string {
 	return fmt.Sprintf("%s://%s", scheme, r.Host)
 }
 
-func deleteLater(path string) {
-	time.Sleep(lifeTime)
-	os.Remove(path)
+// conditionalGet adds an ETag derived from the file's mtime/size and
+// honors If-None-Match, returning 304 without touching next when it
+// matches. If-Modified-Since is left to next (http.FileServer's
+// ServeContent already handles it from the file's mtime).
+func conditionalGet(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info, err := os.Stat(filepath.Join(tempDir, filepath.Clean("/"+r.URL.Path)))
+		if err == nil && !info.IsDir() {
+			etag := fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size())
+			w.Header().Set("ETag", etag)
+
+			if r.Header.Get("If-None-Match") == etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
 }
 
 func cors(h http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
 		w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
 
 		if r.Method == http.MethodOptions {
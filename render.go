@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	wkhtml "github.com/SebastiaanKlippert/go-wkhtmltopdf"
+)
+
+const defaultMarginMM = 6
+
+// RenderOptions are the per-request rendering knobs layered on top of the
+// A4/6mm-margin defaults. A zero-valued field falls back to that default
+// rather than being passed to wkhtmltopdf.
+type RenderOptions struct {
+	PageSize     string       `json:"page_size"`     // A4, Letter, Legal, ... (default A4)
+	PageWidthMM  float64      `json:"page_width_mm"` // used instead of PageSize for a custom size
+	PageHeightMM float64      `json:"page_height_mm"`
+	Orientation  string       `json:"orientation"` // Portrait or Landscape
+	DPI          uint         `json:"dpi"`
+	Grayscale    bool         `json:"grayscale"`
+	Margins      *PageMargins `json:"margins"`
+	HeaderHTML   string       `json:"header_html"`
+	FooterHTML   string       `json:"footer_html"`
+	TOC          bool         `json:"toc"`
+	JSDelayMS    uint         `json:"js_delay_ms"`
+	Pages        []PageInput  `json:"pages"`
+}
+
+// PageMargins overrides one or more page-side margins in millimeters.
+// Unset sides keep the defaultMarginMM default.
+type PageMargins struct {
+	Top    *uint `json:"top"`
+	Bottom *uint `json:"bottom"`
+	Left   *uint `json:"left"`
+	Right  *uint `json:"right"`
+}
+
+// PageInput is one entry of a multi-page document: either a URL or raw
+// HTML. Each becomes its own wkhtmltopdf page, concatenated in order into
+// a single output PDF.
+type PageInput struct {
+	URL  string `json:"url"`
+	HTML string `json:"html"`
+}
+
+// renderPDF applies opts to a fresh PDFGenerator, adds one page per entry
+// in opts.Pages (falling back to a single page built from html if that's
+// empty), and writes the result to output.
+func renderPDF(html string, opts RenderOptions, output string, stderr io.Writer) error {
+	pdfg, err := newGenerator(html, opts, stderr)
+	if err != nil {
+		return err
+	}
+
+	if err := pdfg.Create(); err != nil {
+		return err
+	}
+
+	return pdfg.WriteFile(output)
+}
+
+func newGenerator(html string, opts RenderOptions, stderr io.Writer) (*wkhtml.PDFGenerator, error) {
+	pdfg, err := wkhtml.NewPDFGenerator()
+	if err != nil {
+		return nil, err
+	}
+
+	applyGlobalOptions(pdfg, opts)
+
+	if stderr != nil {
+		pdfg.SetStderr(stderr)
+	}
+
+	inputs := opts.Pages
+	if len(inputs) == 0 {
+		inputs = []PageInput{{HTML: html}}
+	}
+
+	for _, in := range inputs {
+		page, err := newPageProvider(in, opts)
+		if err != nil {
+			return nil, err
+		}
+		pdfg.AddPage(page)
+	}
+
+	return pdfg, nil
+}
+
+func newPageProvider(in PageInput, opts RenderOptions) (wkhtml.PageProvider, error) {
+	if in.HTML == "" && in.URL == "" {
+		return nil, fmt.Errorf("page requires either url or html")
+	}
+
+	if in.URL != "" {
+		page := wkhtml.NewPage(in.URL)
+		applyPageOptions(&page.PageOptions, opts)
+		return page, nil
+	}
+
+	page := wkhtml.NewPageReader(io.NopCloser(&stringReader{in.HTML}))
+	applyPageOptions(&page.PageOptions, opts)
+	return page, nil
+}
+
+func applyPageOptions(page *wkhtml.PageOptions, opts RenderOptions) {
+	page.EnableLocalFileAccess.Set(true)
+
+	if opts.JSDelayMS != 0 {
+		page.JavascriptDelay.Set(opts.JSDelayMS)
+	}
+	if opts.HeaderHTML != "" {
+		page.HeaderHTML.Set(opts.HeaderHTML)
+	}
+	if opts.FooterHTML != "" {
+		page.FooterHTML.Set(opts.FooterHTML)
+	}
+}
+
+func applyGlobalOptions(pdfg *wkhtml.PDFGenerator, opts RenderOptions) {
+	switch {
+	case opts.PageWidthMM != 0 || opts.PageHeightMM != 0:
+		pdfg.PageWidth.Set(uint(opts.PageWidthMM))
+		pdfg.PageWidthUnit.Set("mm")
+		pdfg.PageHeight.Set(uint(opts.PageHeightMM))
+		pdfg.PageHeightUnit.Set("mm")
+	case opts.PageSize != "":
+		pdfg.PageSize.Set(opts.PageSize)
+	default:
+		pdfg.PageSize.Set(wkhtml.PageSizeA4)
+	}
+
+	if opts.Orientation != "" {
+		pdfg.Orientation.Set(opts.Orientation)
+	}
+
+	if opts.DPI != 0 {
+		pdfg.Dpi.Set(opts.DPI)
+	}
+
+	if opts.Grayscale {
+		pdfg.Grayscale.Set(true)
+	}
+
+	pdfg.MarginTop.Set(marginOrDefault(opts.margin("top")))
+	pdfg.MarginBottom.Set(marginOrDefault(opts.margin("bottom")))
+	pdfg.MarginLeft.Set(marginOrDefault(opts.margin("left")))
+	pdfg.MarginRight.Set(marginOrDefault(opts.margin("right")))
+
+	if opts.TOC {
+		pdfg.TOC.Include = true
+	}
+}
+
+func (o RenderOptions) margin(side string) *uint {
+	if o.Margins == nil {
+		return nil
+	}
+	switch side {
+	case "top":
+		return o.Margins.Top
+	case "bottom":
+		return o.Margins.Bottom
+	case "left":
+		return o.Margins.Left
+	case "right":
+		return o.Margins.Right
+	default:
+		return nil
+	}
+}
+
+func marginOrDefault(v *uint) uint {
+	if v == nil {
+		return defaultMarginMM
+	}
+	return *v
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Storage abstracts where converted PDFs live after generation, so the
+// service can run with a local disk behind one node or hand files off to
+// an object store and scale horizontally without a shared volume.
+type Storage interface {
+	// Put stores the contents of r under name and makes it expire after
+	// ttl.
+	Put(name string, r io.Reader, ttl time.Duration) error
+	// URL returns a link the caller can use to fetch name, valid for at
+	// least ttl. For local storage this points back at this server; for
+	// remote backends it's a presigned URL straight to the object.
+	URL(name string, ttl time.Duration) (string, error)
+	// Delete removes name immediately.
+	Delete(name string) error
+}
+
+// newStorageFromEnv selects a Storage backend based on the STORAGE_BACKEND
+// env var (local, s3, gcs), defaulting to local disk under tempDir so the
+// service keeps working unconfigured.
+func newStorageFromEnv() (Storage, error) {
+	switch os.Getenv("STORAGE_BACKEND") {
+	case "s3":
+		return newS3Storage()
+	case "gcs":
+		return newGCSStorage()
+	case "", "local":
+		return newLocalStorage(tempDir)
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_BACKEND %q", os.Getenv("STORAGE_BACKEND"))
+	}
+}
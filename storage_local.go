@@ -0,0 +1,63 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	expirationIndexFile = ".expirations.json"
+	sweepInterval       = 30 * time.Second
+)
+
+// localStorage stores files directly on disk under dir and serves them
+// back via this server's own /files/ route. Expirations are tracked in an
+// expiryIndex so a restart doesn't leak files the way a bare per-file
+// delete goroutine would; a background sweeper reaps anything whose time has
+// passed, on this process or whichever one picks the index back up.
+type localStorage struct {
+	dir   string
+	index *expiryIndex
+}
+
+func newLocalStorage(dir string) (*localStorage, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	s := &localStorage{dir: dir, index: newExpiryIndex(filepath.Join(dir, expirationIndexFile))}
+	go s.index.sweepLoop(sweepInterval, s.Delete)
+
+	return s, nil
+}
+
+func (s *localStorage) Put(name string, r io.Reader, ttl time.Duration) error {
+	dst, err := os.Create(filepath.Join(s.dir, name))
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, r); err != nil {
+		return err
+	}
+
+	s.index.set(name, time.Now().Add(ttl))
+	return nil
+}
+
+func (s *localStorage) URL(name string, ttl time.Duration) (string, error) {
+	return "/files/" + name, nil
+}
+
+func (s *localStorage) Delete(name string) error {
+	s.index.remove(name)
+
+	err := os.Remove(filepath.Join(s.dir, name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
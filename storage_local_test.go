@@ -0,0 +1,70 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLocalStoragePutURLDelete(t *testing.T) {
+	s, err := newLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("newLocalStorage: %v", err)
+	}
+
+	if err := s.Put("report.pdf", strings.NewReader("pdf-bytes"), time.Minute); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	url, err := s.URL("report.pdf", time.Minute)
+	if err != nil {
+		t.Fatalf("URL: %v", err)
+	}
+	if url != "/files/report.pdf" {
+		t.Fatalf("unexpected URL %q", url)
+	}
+
+	if err := s.Delete("report.pdf"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := s.Delete("report.pdf"); err != nil {
+		t.Fatalf("Delete should be idempotent on an already-removed file: %v", err)
+	}
+}
+
+func TestExpiryIndexPersistsAcrossReload(t *testing.T) {
+	path := t.TempDir() + "/expirations.json"
+
+	idx := newExpiryIndex(path)
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	idx.set("a.pdf", expiresAt)
+
+	reloaded := newExpiryIndex(path)
+
+	if got := reloaded.expired(expiresAt.Add(-time.Minute)); len(got) != 0 {
+		t.Fatalf("expected a.pdf to not be expired yet, got %v", got)
+	}
+	if got := reloaded.expired(expiresAt.Add(time.Minute)); len(got) != 1 || got[0] != "a.pdf" {
+		t.Fatalf("expected a.pdf to be expired after its time passed, got %v", got)
+	}
+}
+
+func TestExpiryIndexSweepDeletesExpiredAndRemovesFromIndex(t *testing.T) {
+	idx := newExpiryIndex(t.TempDir() + "/expirations.json")
+	idx.set("old.pdf", time.Now().Add(-time.Minute))
+	idx.set("fresh.pdf", time.Now().Add(time.Hour))
+
+	var deleted []string
+	idx.sweep(func(name string) error {
+		deleted = append(deleted, name)
+		idx.remove(name)
+		return nil
+	})
+
+	if len(deleted) != 1 || deleted[0] != "old.pdf" {
+		t.Fatalf("expected only old.pdf to be swept, got %v", deleted)
+	}
+	if remaining := idx.expired(time.Now().Add(2 * time.Hour)); len(remaining) != 1 || remaining[0] != "fresh.pdf" {
+		t.Fatalf("expected fresh.pdf to remain in the index, got %v", remaining)
+	}
+}
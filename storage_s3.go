@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+const s3ExpirationIndexFile = ".s3-expirations.json"
+
+// s3Storage stores files in an S3-compatible bucket and hands back
+// presigned GetObject URLs instead of routing downloads through this
+// server, which is what lets it run across multiple instances without a
+// shared volume. A presigned URL expiring doesn't delete the underlying
+// object, so s3Storage sweeps its own expiryIndex the same way
+// localStorage does, instead of leaving objects in the bucket forever.
+type s3Storage struct {
+	bucket  string
+	client  *s3.Client
+	presign *s3.PresignClient
+	index   *expiryIndex
+}
+
+// newS3Storage builds an s3Storage from S3_BUCKET and the usual AWS env
+// vars/credential chain (AWS_REGION, AWS_ACCESS_KEY_ID, ...). S3_ENDPOINT
+// can override the endpoint for S3-compatible stores (MinIO, R2, etc).
+func newS3Storage() (*s3Storage, error) {
+	bucket := os.Getenv("S3_BUCKET")
+	if bucket == "" {
+		return nil, fmt.Errorf("S3_BUCKET is required for STORAGE_BACKEND=s3")
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("loading AWS config: %w", err)
+	}
+
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if endpoint := os.Getenv("S3_ENDPOINT"); endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+			o.UsePathStyle = true
+		}
+	})
+
+	s := &s3Storage{
+		bucket:  bucket,
+		client:  client,
+		presign: s3.NewPresignClient(client),
+		index:   newExpiryIndex(filepath.Join(tempDir, s3ExpirationIndexFile)),
+	}
+	go s.index.sweepLoop(sweepInterval, s.Delete)
+
+	return s, nil
+}
+
+func (s *s3Storage) Put(name string, r io.Reader, ttl time.Duration) error {
+	uploader := manager.NewUploader(s.client)
+	_, err := uploader.Upload(context.Background(), &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(name),
+		Body:   r,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.index.set(name, time.Now().Add(ttl))
+	return nil
+}
+
+func (s *s3Storage) URL(name string, ttl time.Duration) (string, error) {
+	req, err := s.presign.PresignGetObject(context.Background(), &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(name),
+	}, s3.WithPresignExpires(ttl))
+	if err != nil {
+		return "", err
+	}
+	return req.URL, nil
+}
+
+func (s *s3Storage) Delete(name string) error {
+	s.index.remove(name)
+
+	_, err := s.client.DeleteObject(context.Background(), &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(name),
+	})
+	return err
+}
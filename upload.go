@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const maxUploadMemory = 32 << 20 // 32MB held in memory before spilling to disk
+
+// cidPattern matches src="cid:foo.png" / href='cid:foo.png' style references
+// so they can be rewritten to resolve against the staged asset directory.
+var cidPattern = regexp.MustCompile(`cid:([\w.\-]+)`)
+
+// multipartConvertHandler is the multipart/form-data counterpart to
+// convertHandler's plain-JSON path. It lets a caller bundle an HTML
+// document together with the local images/CSS/fonts it references,
+// instead of requiring those assets to already be hosted somewhere.
+func multipartConvertHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	start := time.Now()
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxHTMLBodyBytes)
+
+	if err := r.ParseMultipartForm(maxUploadMemory); err != nil {
+		writeError(w, "invalid multipart body", http.StatusBadRequest)
+		return
+	}
+
+	html := r.FormValue("html")
+	if html == "" {
+		writeError(w, "html is required", http.StatusBadRequest)
+		return
+	}
+
+	stagingDir, err := stageUploadedAssets(r)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer os.RemoveAll(stagingDir)
+
+	html = cidPattern.ReplaceAllString(html, "$1")
+
+	htmlPath := filepath.Join(stagingDir, "index.html")
+	if err := os.WriteFile(htmlPath, []byte(html), 0644); err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	prefix := r.FormValue("prefix")
+	if prefix == "" {
+		prefix = "file"
+	}
+
+	opts := RenderOptions{Pages: []PageInput{{URL: htmlPath}}}
+	name, err := convertAndStore("", opts, prefix, lifeTime, nil)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	link, err := store.URL(name, lifeTime)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := SuccessResponse{
+		Success:     true,
+		Link:        resolveLink(link, getServerURL(r)),
+		ExpiresAt:   time.Now().Add(lifeTime).UTC(),
+		TimeElapsed: time.Since(start).Milliseconds(),
+	}
+
+	json.NewEncoder(w).Encode(resp)
+}
+
+// stageUploadedAssets writes every "file" part of the multipart form into
+// a fresh per-request subdirectory under tempDir, so relative and cid:
+// references in the accompanying HTML resolve against real files on disk.
+func stageUploadedAssets(r *http.Request) (string, error) {
+	stagingDir := filepath.Join(tempDir, "uploads", buildJobID())
+	if err := os.MkdirAll(stagingDir, 0755); err != nil {
+		return "", err
+	}
+
+	parts := r.MultipartForm.File["file"]
+	for _, part := range parts {
+		if err := stageUploadedFile(stagingDir, part); err != nil {
+			os.RemoveAll(stagingDir)
+			return "", err
+		}
+	}
+
+	return stagingDir, nil
+}
+
+func stageUploadedFile(stagingDir string, part *multipart.FileHeader) error {
+	src, err := part.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	name := filepath.Base(part.Filename)
+	if name == "" || name == "." || strings.Contains(name, "..") {
+		return fmt.Errorf("invalid asset filename %q", part.Filename)
+	}
+
+	dst, err := os.Create(filepath.Join(stagingDir, name))
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	_, err = io.Copy(dst, src)
+	return err
+}